@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand"
+	"sort"
+	"time"
+)
+
+// invokeAnkiConnectWithBackoff wraps invokeAnkiConnect with a retrying,
+// exponentially-backed-off caller so large backfills don't hammer
+// AnkiConnect with a burst of requests.
+func invokeAnkiConnectWithBackoff(ctx context.Context, action string, params map[string]interface{}) (interface{}, error) {
+	const (
+		maxAttempts = 5
+		baseDelay   = 200 * time.Millisecond
+		maxDelay    = 5 * time.Second
+	)
+
+	var lastErr error
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		if attempt > 0 {
+			delay := baseDelay * time.Duration(1<<uint(attempt-1))
+			if delay > maxDelay {
+				delay = maxDelay
+			}
+			delay += time.Duration(rand.Int63n(int64(baseDelay)))
+
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+
+		result, err := invokeAnkiConnect(ctx, action, params)
+		if err == nil {
+			return result, nil
+		}
+		lastErr = err
+	}
+
+	return nil, fmt.Errorf("giving up on %q after %d attempts: %w", action, maxAttempts, lastErr)
+}
+
+// backfillWindow resolves the -since / -days flags into a concrete number
+// of past days to reconstruct, counting today as day 1.
+func backfillWindow() (int, error) {
+	if *since != "" {
+		sinceDate, err := time.Parse("2006-01-02", *since)
+		if err != nil {
+			return 0, fmt.Errorf("invalid -since date %q: %w", *since, err)
+		}
+		n := int(time.Since(sinceDate).Hours()/24) + 1
+		if n < 1 {
+			n = 1
+		}
+		return n, nil
+	}
+	return *days, nil
+}
+
+// runBackfill reconstructs daily stats for the window requested by -since
+// or -days, using rated:N to find cards reviewed in that window and the
+// cards' review log to bucket each card into the day it was actually
+// reviewed on.
+func runBackfill(ctx context.Context, sink StatsSink) error {
+	n, err := backfillWindow()
+	if err != nil {
+		return err
+	}
+	if n < 1 {
+		return fmt.Errorf("backfill window must be at least 1 day")
+	}
+
+	logf("Backfilling the past %d day(s)...\n", n)
+
+	query := withDeckFilter(fmt.Sprintf("rated:%d", n))
+	result, err := invokeAnkiConnectWithBackoff(ctx, "findCards", map[string]interface{}{
+		"query": query,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to find cards matching %q: %w", query, err)
+	}
+
+	cardIDs, ok := result.([]interface{})
+	if !ok {
+		return fmt.Errorf("unexpected response format for findCards result: %T", result)
+	}
+	logf("Found %d card IDs reviewed in the last %d day(s)\n", len(cardIDs), n)
+
+	dayBuckets, err := bucketReviewsByDay(ctx, cardIDs)
+	if err != nil {
+		return err
+	}
+
+	dates := make([]string, 0, len(dayBuckets))
+	for date := range dayBuckets {
+		dates = append(dates, date)
+	}
+	sort.Strings(dates)
+
+	for _, date := range dates {
+		dayCardIDs := dayBuckets[date]
+		cards, err := cardsInfo(ctx, dayCardIDs)
+		if err != nil {
+			log.Printf("Warning: failed to fetch card info for %s: %v. Skipping day.", date, err)
+			continue
+		}
+
+		dayGroups := make(CardGroups)
+		for _, card := range cards {
+			if card.Word != "" {
+				dayGroups.Set(card.Tag, card.Word, card.Status)
+			}
+		}
+		if dayGroups.Len() == 0 {
+			continue
+		}
+
+		day, err := time.Parse("2006-01-02", date)
+		if err != nil {
+			return fmt.Errorf("failed to parse bucketed date %q: %w", date, err)
+		}
+		if err := sink.Write(day, dayGroups); err != nil {
+			return fmt.Errorf("failed to write backfilled stats for %s: %w", date, err)
+		}
+		logf("Backfilled %d cards for %s\n", dayGroups.Len(), date)
+	}
+
+	return nil
+}
+
+// bucketReviewsByDay fetches the review log for cardIDs and groups the
+// IDs by the calendar day each one was most recently reviewed on.
+func bucketReviewsByDay(ctx context.Context, cardIDs []interface{}) (map[string][]interface{}, error) {
+	buckets := make(map[string][]interface{})
+
+	for i := 0; i < len(cardIDs); i += cardBatchSize {
+		end := i + cardBatchSize
+		if end > len(cardIDs) {
+			end = len(cardIDs)
+		}
+		batch := cardIDs[i:end]
+
+		result, err := invokeAnkiConnectWithBackoff(ctx, "getReviewsOfCards", map[string]interface{}{
+			"cards": batch,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get review history: %w", err)
+		}
+
+		reviewsByCard, ok := result.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("unexpected response format for getReviewsOfCards result: %T", result)
+		}
+
+		for _, cardID := range batch {
+			key := fmt.Sprintf("%v", cardID)
+			reviews, ok := reviewsByCard[key].([]interface{})
+			if !ok || len(reviews) == 0 {
+				continue
+			}
+
+			latest := reviews[len(reviews)-1]
+			review, ok := latest.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			reviewTimeMs, ok := review["id"].(float64)
+			if !ok {
+				continue
+			}
+
+			date := time.UnixMilli(int64(reviewTimeMs)).Format("2006-01-02")
+			buckets[date] = append(buckets[date], cardID)
+		}
+	}
+
+	return buckets, nil
+}
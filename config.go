@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Mapping routes cards from one deck/note-type pair to the field that
+// holds the word to log, and the tag their stats should be grouped under.
+type Mapping struct {
+	Deck     string `toml:"deck"`
+	NoteType string `toml:"noteType"`
+	Field    string `toml:"field"`
+	Tag      string `toml:"tag"`
+}
+
+// Config is the optional ~/.config/anki-stats/config.toml file describing
+// how to handle users with multiple decks and note types.
+type Config struct {
+	Mappings []Mapping `toml:"mappings"`
+}
+
+// loadConfig reads and parses the config file at path. A missing file is
+// not an error: it returns (nil, nil), meaning "no config, use defaults".
+func loadConfig(path string) (*Config, error) {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	var cfg Config
+	if _, err := toml.DecodeFile(path, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %q: %w", path, err)
+	}
+
+	for i, m := range cfg.Mappings {
+		if m.Deck == "" || m.Field == "" || m.Tag == "" {
+			return nil, fmt.Errorf("mapping #%d must set deck, field, and tag", i)
+		}
+	}
+
+	return &cfg, nil
+}
+
+// matchMapping returns the first configured mapping whose deck/note-type
+// applies to cardInfo (a raw cardsInfo entry), or nil if cfg is unset or no
+// mapping matches.
+func matchMapping(cardInfo map[string]interface{}) *Mapping {
+	if cfg == nil {
+		return nil
+	}
+
+	deckName, _ := cardInfo["deckName"].(string)
+	modelName, _ := cardInfo["modelName"].(string)
+
+	for i, m := range cfg.Mappings {
+		if deckName != m.Deck && !strings.HasPrefix(deckName, m.Deck+"::") {
+			continue
+		}
+		if m.NoteType != "" && m.NoteType != modelName {
+			continue
+		}
+		return &cfg.Mappings[i]
+	}
+
+	return nil
+}
+
+// withDeckFilter ANDs baseQuery with an OR of every configured deck, so a
+// single findCards call covers all configured decks at once. If cfg has no
+// mappings, baseQuery is returned unchanged.
+func withDeckFilter(baseQuery string) string {
+	if cfg == nil || len(cfg.Mappings) == 0 {
+		return baseQuery
+	}
+
+	clauses := make([]string, len(cfg.Mappings))
+	for i, m := range cfg.Mappings {
+		clauses[i] = fmt.Sprintf("deck:%q", m.Deck)
+	}
+
+	return fmt.Sprintf("(%s) %s", strings.Join(clauses, " OR "), baseQuery)
+}
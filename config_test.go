@@ -0,0 +1,63 @@
+package main
+
+import "testing"
+
+func TestMatchMapping(t *testing.T) {
+	old := cfg
+	defer func() { cfg = old }()
+
+	cfg = &Config{
+		Mappings: []Mapping{
+			{Deck: "Spanish", NoteType: "Basic", Field: "Front", Tag: "es"},
+			{Deck: "Japanese", Field: "Word", Tag: "ja"},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		deckName  string
+		modelName string
+		wantTag   string
+		wantNil   bool
+	}{
+		{name: "exact deck and note type match", deckName: "Spanish", modelName: "Basic", wantTag: "es"},
+		{name: "subdeck matches parent mapping", deckName: "Spanish::Verbs", modelName: "Basic", wantTag: "es"},
+		{name: "note type mismatch is skipped", deckName: "Spanish", modelName: "Cloze", wantNil: true},
+		{name: "mapping with no note type matches any model", deckName: "Japanese", modelName: "Cloze", wantTag: "ja"},
+		{name: "unconfigured deck matches nothing", deckName: "French", modelName: "Basic", wantNil: true},
+		{name: "deck name prefix without :: separator does not match", deckName: "SpanishExtra", modelName: "Basic", wantNil: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := matchMapping(map[string]interface{}{
+				"deckName":  tt.deckName,
+				"modelName": tt.modelName,
+			})
+
+			if tt.wantNil {
+				if got != nil {
+					t.Fatalf("matchMapping(%q, %q) = %+v, want nil", tt.deckName, tt.modelName, got)
+				}
+				return
+			}
+
+			if got == nil {
+				t.Fatalf("matchMapping(%q, %q) = nil, want tag %q", tt.deckName, tt.modelName, tt.wantTag)
+			}
+			if got.Tag != tt.wantTag {
+				t.Errorf("matchMapping(%q, %q).Tag = %q, want %q", tt.deckName, tt.modelName, got.Tag, tt.wantTag)
+			}
+		})
+	}
+}
+
+func TestMatchMappingNoConfig(t *testing.T) {
+	old := cfg
+	defer func() { cfg = old }()
+	cfg = nil
+
+	if got := matchMapping(map[string]interface{}{"deckName": "Spanish"}); got != nil {
+		t.Fatalf("matchMapping() with nil cfg = %+v, want nil", got)
+	}
+}
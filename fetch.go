@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/cheggaaa/pb/v3"
+)
+
+const (
+	// cardBatchSize is how many card IDs are requested from AnkiConnect's
+	// cardsInfo in a single round-trip.
+	cardBatchSize = 100
+	// fetchWorkers bounds how many batches are in flight at once.
+	fetchWorkers = 4
+)
+
+// cardsInfo fetches info for a batch of card IDs in a single AnkiConnect call.
+func cardsInfo(ctx context.Context, cardIDs []interface{}) ([]Card, error) {
+	result, err := invokeAnkiConnect(ctx, "cardsInfo", map[string]interface{}{
+		"cards": cardIDs,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	rawCards, ok := result.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("unexpected response format for cardsInfo result: %T", result)
+	}
+
+	cards := make([]Card, 0, len(rawCards))
+	for i, raw := range rawCards {
+		cardInfo, ok := raw.(map[string]interface{})
+		if !ok {
+			log.Printf("Warning: skipping card with unexpected info format: %T", raw)
+			continue
+		}
+
+		var cardID interface{}
+		if i < len(cardIDs) {
+			cardID = cardIDs[i]
+		}
+
+		card, err := parseCardInfo(cardID, cardInfo)
+		if err != nil {
+			continue
+		}
+		cards = append(cards, card)
+	}
+
+	return cards, nil
+}
+
+// fetchCards splits cardIDs into batches and fetches them concurrently over
+// a bounded worker pool, reporting progress on a bar unless disabled. If ctx
+// is cancelled (e.g. by Ctrl-C), it stops dispatching new batches and returns
+// whatever results were already collected, along with ctx.Err(). Cards are
+// grouped by their configured mapping tag (see config.go); cards with no
+// matching mapping are grouped under the empty tag.
+func fetchCards(ctx context.Context, cardIDs []interface{}) (CardGroups, int, int, int, error) {
+	groups := make(CardGroups)
+	processedCount := 0
+	skippedCount := 0
+	newWordCount := 0
+	var mu sync.Mutex
+
+	var batches [][]interface{}
+	for i := 0; i < len(cardIDs); i += cardBatchSize {
+		end := i + cardBatchSize
+		if end > len(cardIDs) {
+			end = len(cardIDs)
+		}
+		batches = append(batches, cardIDs[i:end])
+	}
+
+	var bar *pb.ProgressBar
+	if !*silent && !*noProgress {
+		bar = pb.Full.Start(len(cardIDs))
+		bar.SetTemplateString(`{{counters . }} {{bar . }} {{percent . }} {{speed . }} {{rtime . "ETA %s"}}`)
+		defer bar.Finish()
+	}
+
+	batchCh := make(chan []interface{})
+	go func() {
+		defer close(batchCh)
+		for _, batch := range batches {
+			select {
+			case batchCh <- batch:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for w := 0; w < fetchWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for batch := range batchCh {
+				cards, err := cardsInfo(ctx, batch)
+				if err != nil {
+					mu.Lock()
+					skippedCount += len(batch)
+					mu.Unlock()
+					if bar != nil {
+						bar.Add(len(batch))
+					}
+					continue
+				}
+
+				mu.Lock()
+				for _, card := range cards {
+					if card.Word == "" {
+						skippedCount++
+						continue
+					}
+					alreadySeen := groups.Has(card.Tag, card.Word)
+					groups.Set(card.Tag, card.Word, card.Status)
+					if !alreadySeen && card.Status == StatusNew {
+						newWordCount++
+					}
+					processedCount++
+				}
+				skippedCount += len(batch) - len(cards)
+				mu.Unlock()
+
+				if bar != nil {
+					bar.Add(len(batch))
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	return groups, processedCount, skippedCount, newWordCount, ctx.Err()
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+)
+
+// gitAddCommit stages filePath and commits it with a machine-parseable
+// summary of the day's review groups. It skips the commit gracefully (not
+// an error) if staging filePath left nothing to commit.
+func gitAddCommit(filePath string, groups CardGroups, newWordCount int) error {
+	if out, err := exec.Command("git", "add", filePath).CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to add file to Git: %w (%s)", err, strings.TrimSpace(string(out)))
+	}
+
+	if err := exec.Command("git", "diff", "--cached", "--quiet", "--", filePath).Run(); err == nil {
+		fmt.Printf("No changes to %q; skipping commit.\n", filePath)
+		return nil
+	}
+
+	dateStr := time.Now().Format("2006-01-02")
+	counts := groups.Count()
+	subject := fmt.Sprintf("Anki stats for %s: %d reviews, %d new words", dateStr, counts.Total, newWordCount)
+	body := commitBody(counts, newWordCount, groups)
+
+	args := []string{"commit", "-m", subject, "-m", body}
+	if *sign {
+		args = append(args, "-S")
+	}
+	if *author != "" {
+		args = append(args, "--author", *author)
+	}
+
+	if out, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to commit changes to Git: %w (%s)", err, strings.TrimSpace(string(out)))
+	}
+
+	fmt.Printf("Successfully added and committed changes to Git with message: %q\n", subject)
+
+	if *push != "" {
+		if out, err := exec.Command("git", "push", *push).CombinedOutput(); err != nil {
+			return fmt.Errorf("failed to push to %q: %w (%s)", *push, err, strings.TrimSpace(string(out)))
+		}
+		fmt.Printf("Pushed to %q\n", *push)
+	}
+
+	return nil
+}
+
+// commitBody renders the machine-parseable commit message body: per-status
+// counts, a per-tag deck breakdown, and the tool's version trailer.
+func commitBody(counts StatusCounts, newWordCount int, groups CardGroups) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Reviews: %d\n", counts.Total)
+	fmt.Fprintf(&b, "New: %d\n", newWordCount)
+	fmt.Fprintf(&b, "Learning: %d\n", counts.Learning)
+	fmt.Fprintf(&b, "Relearning: %d\n", counts.Relearning)
+	fmt.Fprintf(&b, "Review: %d\n", counts.Review)
+
+	tags := make([]string, 0, len(groups))
+	for tag := range groups {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	b.WriteString("Deck-Breakdown:\n")
+	for _, tag := range tags {
+		name := tag
+		if name == "" {
+			name = "default"
+		}
+		fmt.Fprintf(&b, "  %s: %d\n", name, len(groups[tag]))
+	}
+
+	fmt.Fprintf(&b, "\nAnki-Stats-Version: %s", toolVersion)
+	return b.String()
+}
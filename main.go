@@ -2,15 +2,18 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
-	"os/exec"
+	"os/signal"
 	"path/filepath"
 	"strings"
+	"syscall"
+	"testing"
 	"time"
 )
 
@@ -25,6 +28,9 @@ type AnkiConnectResponse struct {
 	Error  *string     `json:"error"`
 }
 
+// toolVersion is recorded in each stats commit's Anki-Stats-Version trailer.
+const toolVersion = "0.1.0"
+
 type CardStatus string
 
 const (
@@ -37,24 +43,125 @@ const (
 type Card struct {
 	Word   string
 	Status CardStatus
+	// Tag is the configured mapping tag the card was routed by (see
+	// config.go), or "" when no config file applies.
+	Tag string
+}
+
+// CardGroups holds a day's cards keyed first by mapping tag ("" for the
+// unconfigured default), then by word.
+type CardGroups map[string]map[string]CardStatus
+
+// Has reports whether word is already recorded under tag.
+func (g CardGroups) Has(tag, word string) bool {
+	_, ok := g[tag][word]
+	return ok
+}
+
+// Set records word's status under tag, creating the group if needed.
+func (g CardGroups) Set(tag, word string, status CardStatus) {
+	if g[tag] == nil {
+		g[tag] = make(map[string]CardStatus)
+	}
+	g[tag][word] = status
+}
+
+// Len returns the total number of cards across all tags.
+func (g CardGroups) Len() int {
+	n := 0
+	for _, cards := range g {
+		n += len(cards)
+	}
+	return n
 }
 
-var tomlFile *string
+// StatusCounts tallies cards by status.
+type StatusCounts struct {
+	Total      int `json:"total"`
+	New        int `json:"new"`
+	Learning   int `json:"learning"`
+	Review     int `json:"review"`
+	Relearning int `json:"relearning"`
+}
+
+// Count tallies every card across all tags by status.
+func (g CardGroups) Count() StatusCounts {
+	var c StatusCounts
+	for _, cards := range g {
+		for _, status := range cards {
+			c.Total++
+			switch status {
+			case StatusNew:
+				c.New++
+			case StatusLearning:
+				c.Learning++
+			case StatusReview:
+				c.Review++
+			case StatusRelearning:
+				c.Relearning++
+			}
+		}
+	}
+	return c
+}
+
+var (
+	tomlFile   *string
+	format     *string
+	silent     *bool
+	noProgress *bool
+	since      *string
+	days       *int
+	configFile *string
+	serveAddr  *string
+	sign       *bool
+	author     *string
+	push       *string
+
+	// cfg is the loaded config file (see config.go), or nil if none was
+	// found or configured.
+	cfg *Config
+)
 
 func init() {
 	defaultPath := "anki_stats.toml"
+	defaultConfigPath := "config.toml"
 	homeDir, err := os.UserHomeDir()
 	if err == nil {
 		defaultPath = filepath.Join(homeDir, ".config", "anki-stats", "anki_stats.toml")
+		defaultConfigPath = filepath.Join(homeDir, ".config", "anki-stats", "config.toml")
 	} else {
 		log.Printf("Warning: Could not determine home directory. Using default path in current directory: %s", defaultPath)
 	}
 
-	tomlFile = flag.String("o", defaultPath, "Path to the output TOML file")
+	tomlFile = flag.String("o", defaultPath, "Path to the output file")
+	format = flag.String("format", "", "Output format: toml, json, csv, or sqlite (default: inferred from -o's extension)")
+	silent = flag.Bool("silent", false, "Suppress all non-error output")
+	noProgress = flag.Bool("no-progress", false, "Disable the progress bar")
+	since = flag.String("since", "", "Backfill stats for every day from this date (YYYY-MM-DD) to today, instead of just today")
+	days = flag.Int("days", 0, "Backfill stats for the past N days, instead of just today")
+	configFile = flag.String("config", defaultConfigPath, "Path to the deck/note-type mapping config file (optional)")
+	serveAddr = flag.String("serve", "", "Address to serve an HTTP stats dashboard on (e.g. :8080), after the one-shot run completes")
+	sign = flag.Bool("sign", false, "GPG-sign the stats commit (git commit -S)")
+	author = flag.String("author", "", "Override the stats commit author, e.g. \"Name <email>\"")
+	push = flag.String("push", "", "Push to this remote after committing stats")
+
+	// Under `go test`, the flags above are still declared (so their default
+	// values are usable by tests) but never parsed: test binaries pass their
+	// own -test.* flags, and flag.Parse() would choke on them.
+	if testing.Testing() {
+		return
+	}
 	flag.Parse()
+
+	loadedCfg, err := loadConfig(*configFile)
+	if err != nil {
+		log.Printf("Warning: Could not load config file %q: %v. Falling back to single-deck mode.", *configFile, err)
+	}
+	cfg = loadedCfg
 }
 
-func invokeAnkiConnect(action string, params map[string]interface{}) (interface{}, error) {
+func invokeAnkiConnect(ctx context.Context, action string, params map[string]interface{}) (interface{}, error) {
 	if params == nil {
 		params = map[string]interface{}{}
 	}
@@ -70,7 +177,13 @@ func invokeAnkiConnect(action string, params map[string]interface{}) (interface{
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	resp, err := http.Post("http://localhost:8765", "application/json", bytes.NewBuffer(requestJSON))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "http://localhost:8765", bytes.NewBuffer(requestJSON))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to send request to AnkiConnect (is Anki running with AnkiConnect on http://localhost:8765?): %w", err)
 	}
@@ -88,38 +201,31 @@ func invokeAnkiConnect(action string, params map[string]interface{}) (interface{
 	return response.Result, nil
 }
 
-func getCardInfo(cardID interface{}) (Card, error) {
+// parseCardInfo extracts a Card from a single element of a cardsInfo result.
+// If a config file is loaded, the card's deck/note type is routed to its
+// configured field and tag (see config.go); otherwise it falls back to the
+// "Word" field, or the first non-empty field if "Word" is missing.
+func parseCardInfo(cardID interface{}, cardInfo map[string]interface{}) (Card, error) {
 	var card Card
 
-	result, err := invokeAnkiConnect("cardsInfo", map[string]interface{}{
-		"cards": []interface{}{cardID},
-	})
-	if err != nil {
-		return card, err
-	}
-
-	cards, ok := result.([]interface{})
-	if !ok || len(cards) == 0 {
-		return card, fmt.Errorf("unexpected response format for card info: %T", result)
-	}
-
-	cardInfo, ok := cards[0].(map[string]interface{})
-	if !ok {
-		return card, fmt.Errorf("unexpected card info format: %T", cards[0])
-	}
-
 	fields, ok := cardInfo["fields"].(map[string]interface{})
 	if !ok {
 		return card, fmt.Errorf("could not get card fields map from card info")
 	}
 
-	wordValue := getFieldValue(fields, "Word")
+	fieldName := "Word"
+	if m := matchMapping(cardInfo); m != nil {
+		fieldName = m.Field
+		card.Tag = m.Tag
+	}
+
+	wordValue := getFieldValue(fields, fieldName)
 	if wordValue == "" {
-		log.Printf("Warning: 'Word' field is empty or missing for card ID %v. Trying other fields.", cardID)
-		for fieldName, _ := range fields {
-			value := getFieldValue(fields, fieldName)
+		log.Printf("Warning: '%s' field is empty or missing for card ID %v. Trying other fields.", fieldName, cardID)
+		for otherField := range fields {
+			value := getFieldValue(fields, otherField)
 			if value != "" {
-				log.Printf("Using field '%s' with value '%s' as fallback.", fieldName, value)
+				log.Printf("Using field '%s' with value '%s' as fallback.", otherField, value)
 				wordValue = value
 				break
 			}
@@ -174,80 +280,16 @@ func getCardStatus(cardInfo map[string]interface{}) CardStatus {
 	}
 }
 
-func appendToTOML(filename string, cardMap map[string]CardStatus) error {
-	dir := filepath.Dir(filename)
-	if err := os.MkdirAll(dir, 0o755); err != nil {
-		return fmt.Errorf("failed to create directory %q: %w", dir, err)
-	}
-
-	existing, err := os.ReadFile(filename)
-	if err != nil && !os.IsNotExist(err) {
-		return fmt.Errorf("failed to read %q: %w", filename, err)
-	}
-
-	today := time.Now().Format("2006-01-02")
-	dateHeader := fmt.Sprintf("[%s]", today)
-
-	var section strings.Builder
-	section.WriteString(dateHeader + "\n")
-	for word, status := range cardMap {
-		escaped := strings.ReplaceAll(word, `"`, `\"`)
-		section.WriteString(fmt.Sprintf("\"%s\" = \"%s\"\n", escaped, status))
-	}
-	newBlock := []byte(section.String())
-
-	start := bytes.Index(existing, []byte(dateHeader))
-	if start != -1 {
-		searchFrom := start + len(dateHeader)
-		next := bytes.Index(existing[searchFrom:], []byte("\n["))
-		var end int
-		if next == -1 {
-			end = len(existing)
-		} else {
-			end = searchFrom + next + 1
-		}
-		existing = append(existing[:start], existing[end:]...)
-		existing = bytes.TrimRight(existing, "\n")
-	}
-
-	if len(existing) > 0 && existing[len(existing)-1] != '\n' {
-		existing = append(existing, '\n')
-	}
-	existing = append(existing, newBlock...)
-
-	data := append(bytes.TrimRight(existing, "\n"), '\n')
-	if err := os.WriteFile(filename, data, 0o644); err != nil {
-		return fmt.Errorf("failed to write %q: %w", filename, err)
-	}
-	return nil
-}
-
-func gitAddCommit(filePath string, numReviews int, numNewWords int) error {
-	_, err := exec.Command("git", "add", filePath).CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("failed to add file to Git: %w", err)
-	}
-
-	now := time.Now()
-	dateStr := now.Format("2006-01-02")
-	commitMessage := fmt.Sprintf("Anki stats for %s: %d reviews, %d new words", dateStr, numReviews, numNewWords)
-
-	_, err = exec.Command("git", "commit", "-m", commitMessage).CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("failed to commit changes to Git: %w", err)
-	}
-
-	fmt.Printf("Successfully added and committed changes to Git with message: \"%s\"\n", commitMessage)
-	return nil
-}
-
 func main() {
 	outputFilePath := *tomlFile
 	if outputFilePath == "" {
 		log.Fatal("Output file path cannot be empty. Please specify with -o.")
 	}
 
-	versionResult, err := invokeAnkiConnect("version", nil)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	versionResult, err := invokeAnkiConnect(ctx, "version", nil)
 	if err != nil {
 		log.Fatalf("Failed to connect to AnkiConnect: %v", err)
 	}
@@ -262,13 +304,25 @@ func main() {
 		versionStr = v
 	}
 
-	fmt.Printf("Connected to AnkiConnect v%s\n", versionStr)
+	logf("Connected to AnkiConnect v%s\n", versionStr)
 
-	uniqueCards := make(map[string]CardStatus)
+	if *since != "" || *days > 0 {
+		sink, err := newStatsSink(outputFilePath, *format)
+		if err != nil {
+			log.Fatalf("Failed to set up output sink: %v", err)
+		}
+		defer sink.Close()
+
+		if err := runBackfill(ctx, sink); err != nil {
+			log.Fatalf("Backfill failed: %v", err)
+		}
+		maybeServe(outputFilePath)
+		return
+	}
 
-	query := "rated:1"
-	fmt.Printf("Querying Anki for cards matching: \"%s\"\n", query)
-	reviewedResult, err := invokeAnkiConnect("findCards", map[string]interface{}{
+	query := withDeckFilter("rated:1")
+	logf("Querying Anki for cards matching: \"%s\"\n", query)
+	reviewedResult, err := invokeAnkiConnect(ctx, "findCards", map[string]interface{}{
 		"query": query,
 	})
 	if err != nil {
@@ -280,57 +334,63 @@ func main() {
 		log.Fatalf("Unexpected response format for findCards result: %T", reviewedResult)
 	}
 
-	fmt.Printf("Found %d card IDs potentially reviewed today\n", len(reviewedCardIDs))
+	logf("Found %d card IDs potentially reviewed today\n", len(reviewedCardIDs))
 
-	processedCount := 0
-	skippedCount := 0
-	newWordCount := 0
-	for _, cardID := range reviewedCardIDs {
-		card, err := getCardInfo(cardID)
-		if err != nil {
-			log.Printf("Warning: Error getting info for card ID %v: %v. Skipping card.", cardID, err)
-			skippedCount++
-			continue
-		}
-
-		if card.Word != "" {
-			_, alreadySeen := uniqueCards[card.Word]
-			uniqueCards[card.Word] = card.Status
-			if !alreadySeen && card.Status == StatusNew {
-				newWordCount++
-			}
-			processedCount++
-		} else {
-			log.Printf("Warning: Card ID %v resulted in an empty Word field after processing. Skipping.", cardID)
-			skippedCount++
-		}
+	groups, processedCount, skippedCount, newWordCount, err := fetchCards(ctx, reviewedCardIDs)
+	if err != nil && groups.Len() == 0 {
+		log.Fatalf("Failed to fetch card info: %v", err)
+	} else if err != nil {
+		log.Printf("Warning: %v. Flushing %d cards collected before cancellation.", err, groups.Len())
 	}
 
-	fmt.Printf("Processed %d cards, skipped %d due to errors or empty word field.\n", processedCount, skippedCount)
+	logf("Processed %d cards, skipped %d due to errors or empty word field.\n", processedCount, skippedCount)
+
+	if groups.Len() > 0 {
+		sink, err := newStatsSink(outputFilePath, *format)
+		if err != nil {
+			log.Fatalf("Failed to set up output sink: %v", err)
+		}
+		defer sink.Close()
 
-	if len(uniqueCards) > 0 {
-		if err := appendToTOML(outputFilePath, uniqueCards); err != nil {
-			log.Fatalf("Failed to write to TOML file '%s': %v", outputFilePath, err)
+		if err := sink.Write(time.Now(), groups); err != nil {
+			log.Fatalf("Failed to write stats to '%s': %v", outputFilePath, err)
 		}
 
-		fmt.Printf("\nSuccessfully logged %d unique cards (%d new) to %s\n", len(uniqueCards), newWordCount, outputFilePath)
+		logf("\nSuccessfully logged %d unique cards (%d new) to %s\n", groups.Len(), newWordCount, outputFilePath)
 
-		fmt.Println("\nSample of logged cards:")
+		logf("\nSample of logged cards:\n")
 		i := 0
-		for word, status := range uniqueCards {
-			if i >= 5 {
-				fmt.Printf("... and %d more\n", len(uniqueCards)-5)
-				break
+	sample:
+		for tag, cards := range groups {
+			for word, status := range cards {
+				if i >= 5 {
+					logf("... and %d more\n", groups.Len()-5)
+					break sample
+				}
+				printableWord := strings.ReplaceAll(word, "\n", " ")
+				if tag != "" {
+					logf("- [%s] \"%s\" = \"%s\"\n", tag, printableWord, status)
+				} else {
+					logf("- \"%s\" = \"%s\"\n", printableWord, status)
+				}
+				i++
 			}
-			printableWord := strings.ReplaceAll(word, "\n", " ")
-			fmt.Printf("- \"%s\" = \"%s\"\n", printableWord, status)
-			i++
 		}
 
-		if err := gitAddCommit(outputFilePath, len(uniqueCards), newWordCount); err != nil {
+		if err := gitAddCommit(outputFilePath, groups, newWordCount); err != nil {
 			log.Printf("Warning: Failed to add and commit changes to Git: %v", err)
 		}
 	} else {
-		fmt.Println("No unique cards with non-empty words found to log today.")
+		logf("No unique cards with non-empty words found to log today.\n")
+	}
+
+	maybeServe(outputFilePath)
+}
+
+// logf prints to stdout unless -silent was given.
+func logf(format string, args ...interface{}) {
+	if *silent {
+		return
 	}
+	fmt.Printf(format, args...)
 }
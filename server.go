@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bufio"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+//go:embed web
+var webFS embed.FS
+
+// DayStats is the JSON shape returned by /api/stats for a single day.
+type DayStats struct {
+	Date string `json:"date"`
+	StatusCounts
+}
+
+// maybeServe starts the embedded HTTP dashboard on -serve's address, if
+// set, and blocks forever. It is a no-op when -serve is empty.
+func maybeServe(statsPath string) {
+	if *serveAddr == "" {
+		return
+	}
+
+	if got := resolveFormat(statsPath, *format); got != "" && got != "toml" {
+		log.Fatalf("-serve only supports the toml output format (parses %q directly), but -format/-o resolved to %q. Rerun with -o pointing at a .toml file or an explicit -format toml.", statsPath, got)
+	}
+
+	webRoot, err := fs.Sub(webFS, "web")
+	if err != nil {
+		log.Fatalf("Failed to load embedded dashboard assets: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/stats", statsHandler(statsPath))
+	mux.HandleFunc("/api/today", todayHandler(statsPath))
+	mux.Handle("/", http.FileServer(http.FS(webRoot)))
+
+	logf("Serving stats dashboard on %s (reading %s)\n", *serveAddr, statsPath)
+	if err := http.ListenAndServe(*serveAddr, mux); err != nil {
+		log.Fatalf("Dashboard server failed: %v", err)
+	}
+}
+
+func statsHandler(statsPath string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		byDate, err := parseStatsTOML(statsPath)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("failed to read stats: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		from := r.URL.Query().Get("from")
+		to := r.URL.Query().Get("to")
+
+		dates := make([]string, 0, len(byDate))
+		for date := range byDate {
+			if from != "" && date < from {
+				continue
+			}
+			if to != "" && date > to {
+				continue
+			}
+			dates = append(dates, date)
+		}
+		sort.Strings(dates)
+
+		days := make([]DayStats, 0, len(dates))
+		for _, date := range dates {
+			days = append(days, DayStats{Date: date, StatusCounts: byDate[date].Count()})
+		}
+
+		writeJSON(w, days)
+	}
+}
+
+func todayHandler(statsPath string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		byDate, err := parseStatsTOML(statsPath)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("failed to read stats: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		today := time.Now().Format("2006-01-02")
+		writeJSON(w, byDate[today])
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Warning: failed to encode JSON response: %v", err)
+	}
+}
+
+// parseStatsTOML reads the on-disk TOML store written by tomlSink and
+// returns its contents keyed by date. It understands both plain "[date]"
+// sections and tagged "[date.tag]" sections.
+func parseStatsTOML(path string) (map[string]CardGroups, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return map[string]CardGroups{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %q: %w", path, err)
+	}
+	defer f.Close()
+
+	byDate := make(map[string]CardGroups)
+	var date, tag string
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			header := line[1 : len(line)-1]
+			if dot := strings.Index(header, "."); dot != -1 {
+				date, tag = header[:dot], header[dot+1:]
+			} else {
+				date, tag = header, ""
+			}
+			if byDate[date] == nil {
+				byDate[date] = make(CardGroups)
+			}
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, " = ")
+		if !ok || date == "" {
+			continue
+		}
+		word := unquoteTOML(key)
+		status := unquoteTOML(value)
+		byDate[date].Set(tag, word, CardStatus(status))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %q: %w", path, err)
+	}
+
+	return byDate, nil
+}
+
+func unquoteTOML(s string) string {
+	s = strings.TrimSpace(s)
+	s = strings.TrimPrefix(s, `"`)
+	s = strings.TrimSuffix(s, `"`)
+	return strings.ReplaceAll(s, `\"`, `"`)
+}
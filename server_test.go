@@ -0,0 +1,76 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeTestStatsFile(t *testing.T, contents string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "stats.toml")
+	if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+		t.Fatalf("failed to write test stats file: %v", err)
+	}
+	return path
+}
+
+func TestParseStatsTOML(t *testing.T) {
+	path := writeTestStatsFile(t, `[2025-01-15]
+"hola" = "new"
+"adios" = "review"
+
+[2025-01-15.es]
+"gracias" = "learning"
+
+[2025-01-16]
+"hello" = "new"
+`)
+
+	byDate, err := parseStatsTOML(path)
+	if err != nil {
+		t.Fatalf("parseStatsTOML: %v", err)
+	}
+
+	if len(byDate) != 2 {
+		t.Fatalf("got %d dates, want 2", len(byDate))
+	}
+
+	if got := byDate["2025-01-15"][""]["hola"]; got != StatusNew {
+		t.Errorf(`["2025-01-15"][""]["hola"] = %q, want %q`, got, StatusNew)
+	}
+	if got := byDate["2025-01-15"][""]["adios"]; got != StatusReview {
+		t.Errorf(`["2025-01-15"][""]["adios"] = %q, want %q`, got, StatusReview)
+	}
+	if got := byDate["2025-01-15"]["es"]["gracias"]; got != StatusLearning {
+		t.Errorf(`["2025-01-15"]["es"]["gracias"] = %q, want %q`, got, StatusLearning)
+	}
+	if got := byDate["2025-01-16"][""]["hello"]; got != StatusNew {
+		t.Errorf(`["2025-01-16"][""]["hello"] = %q, want %q`, got, StatusNew)
+	}
+}
+
+func TestParseStatsTOMLMissingFile(t *testing.T) {
+	byDate, err := parseStatsTOML(filepath.Join(t.TempDir(), "does-not-exist.toml"))
+	if err != nil {
+		t.Fatalf("parseStatsTOML on missing file: %v", err)
+	}
+	if len(byDate) != 0 {
+		t.Errorf("got %d dates for missing file, want 0", len(byDate))
+	}
+}
+
+func TestParseStatsTOMLEscapedQuotes(t *testing.T) {
+	path := writeTestStatsFile(t, `[2025-01-15]
+"say \"hi\"" = "new"
+`)
+
+	byDate, err := parseStatsTOML(path)
+	if err != nil {
+		t.Fatalf("parseStatsTOML: %v", err)
+	}
+
+	if _, ok := byDate["2025-01-15"][""][`say "hi"`]; !ok {
+		t.Errorf("expected unescaped word %q in %+v", `say "hi"`, byDate["2025-01-15"])
+	}
+}
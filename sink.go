@@ -0,0 +1,284 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// StatsSink persists a day's worth of card statuses to a backing store.
+// Write may be called multiple times (once per day) against the same sink.
+// groups is keyed by mapping tag, with "" being the default, unconfigured
+// group (see config.go).
+type StatsSink interface {
+	Write(date time.Time, groups CardGroups) error
+	Close() error
+}
+
+// resolveFormat returns the effective output format for path: format itself
+// if set, otherwise one inferred from path's file extension.
+func resolveFormat(path string, format string) string {
+	if format == "" {
+		format = strings.TrimPrefix(filepath.Ext(path), ".")
+	}
+	return strings.ToLower(format)
+}
+
+// newStatsSink builds a StatsSink for path. If format is empty, it is
+// inferred from path's file extension.
+func newStatsSink(path string, format string) (StatsSink, error) {
+	switch resolveFormat(path, format) {
+	case "", "toml":
+		return &tomlSink{path: path}, nil
+	case "json":
+		return &jsonSink{path: path}, nil
+	case "csv":
+		return &csvSink{path: path}, nil
+	case "sqlite", "db", "sqlite3":
+		return newSQLiteSink(path)
+	default:
+		return nil, fmt.Errorf("unsupported output format %q", resolveFormat(path, format))
+	}
+}
+
+// tomlSink writes one `[YYYY-MM-DD]` section per day, or one
+// `[YYYY-MM-DD.tag]` section per mapping tag when the cards are grouped,
+// replacing any existing section(s) for the same date. This is the
+// original on-disk format.
+type tomlSink struct {
+	path string
+}
+
+func (s *tomlSink) Write(date time.Time, groups CardGroups) error {
+	dir := filepath.Dir(s.path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create directory %q: %w", dir, err)
+	}
+
+	existing, err := os.ReadFile(s.path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read %q: %w", s.path, err)
+	}
+
+	dateStr := date.Format("2006-01-02")
+	for tag, cards := range groups {
+		header := fmt.Sprintf("[%s]", dateStr)
+		if tag != "" {
+			header = fmt.Sprintf("[%s.%s]", dateStr, tag)
+		}
+
+		var section strings.Builder
+		section.WriteString(header + "\n")
+		for word, status := range cards {
+			escaped := strings.ReplaceAll(word, `"`, `\"`)
+			section.WriteString(fmt.Sprintf("\"%s\" = \"%s\"\n", escaped, status))
+		}
+		newBlock := []byte(section.String())
+
+		start := bytes.Index(existing, []byte(header))
+		if start != -1 {
+			searchFrom := start + len(header)
+			next := bytes.Index(existing[searchFrom:], []byte("\n["))
+			var end int
+			if next == -1 {
+				end = len(existing)
+			} else {
+				end = searchFrom + next + 1
+			}
+			existing = append(existing[:start], existing[end:]...)
+			existing = bytes.TrimRight(existing, "\n")
+		}
+
+		if len(existing) > 0 && existing[len(existing)-1] != '\n' {
+			existing = append(existing, '\n')
+		}
+		existing = append(existing, newBlock...)
+	}
+
+	data := append(bytes.TrimRight(existing, "\n"), '\n')
+	if err := os.WriteFile(s.path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write %q: %w", s.path, err)
+	}
+	return nil
+}
+
+func (s *tomlSink) Close() error { return nil }
+
+// jsonSink stores a single JSON object keyed by date, each value keyed by
+// mapping tag ("" for the default group), each of those a map of word to
+// status: {"2025-01-15": {"": {"word": "new"}, "vocab": {...}}}.
+type jsonSink struct {
+	path string
+}
+
+func (s *jsonSink) Write(date time.Time, groups CardGroups) error {
+	dir := filepath.Dir(s.path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create directory %q: %w", dir, err)
+	}
+
+	store := make(map[string]CardGroups)
+	existing, err := os.ReadFile(s.path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read %q: %w", s.path, err)
+	}
+	if len(existing) > 0 {
+		if err := json.Unmarshal(existing, &store); err != nil {
+			return fmt.Errorf("failed to parse existing JSON in %q: %w", s.path, err)
+		}
+	}
+
+	store[date.Format("2006-01-02")] = groups
+
+	data, err := json.MarshalIndent(store, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal JSON: %w", err)
+	}
+	if err := os.WriteFile(s.path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write %q: %w", s.path, err)
+	}
+	return nil
+}
+
+func (s *jsonSink) Close() error { return nil }
+
+// csvSink appends one "date,tag,word,status" row per card, replacing any
+// existing rows for the same date.
+type csvSink struct {
+	path string
+}
+
+func (s *csvSink) Write(date time.Time, groups CardGroups) error {
+	dir := filepath.Dir(s.path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create directory %q: %w", dir, err)
+	}
+
+	dateStr := date.Format("2006-01-02")
+
+	var rows [][]string
+	if existing, err := os.ReadFile(s.path); err == nil && len(existing) > 0 {
+		r := csv.NewReader(bytes.NewReader(existing))
+		records, err := r.ReadAll()
+		if err != nil {
+			return fmt.Errorf("failed to parse existing CSV in %q: %w", s.path, err)
+		}
+		for _, record := range records {
+			if len(record) > 0 && record[0] == dateStr {
+				continue
+			}
+			rows = append(rows, record)
+		}
+	} else if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read %q: %w", s.path, err)
+	}
+
+	tags := make([]string, 0, len(groups))
+	for tag := range groups {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	for _, tag := range tags {
+		words := make([]string, 0, len(groups[tag]))
+		for word := range groups[tag] {
+			words = append(words, word)
+		}
+		sort.Strings(words)
+		for _, word := range words {
+			rows = append(rows, []string{dateStr, tag, word, string(groups[tag][word])})
+		}
+	}
+
+	f, err := os.Create(s.path)
+	if err != nil {
+		return fmt.Errorf("failed to create %q: %w", s.path, err)
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	if err := w.WriteAll(rows); err != nil {
+		return fmt.Errorf("failed to write %q: %w", s.path, err)
+	}
+	return nil
+}
+
+func (s *csvSink) Close() error { return nil }
+
+// sqliteSink stores one row per (date, word, status) in a `stats` table,
+// so history can be queried with SQL.
+type sqliteSink struct {
+	db *sql.DB
+}
+
+func newSQLiteSink(path string) (*sqliteSink, error) {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create directory %q: %w", dir, err)
+	}
+
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sqlite database %q: %w", path, err)
+	}
+
+	const schema = `
+CREATE TABLE IF NOT EXISTS stats (
+	date   TEXT NOT NULL,
+	tag    TEXT NOT NULL DEFAULT '',
+	word   TEXT NOT NULL,
+	status TEXT NOT NULL,
+	PRIMARY KEY (date, tag, word)
+);`
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create stats table: %w", err)
+	}
+
+	return &sqliteSink{db: db}, nil
+}
+
+func (s *sqliteSink) Write(date time.Time, groups CardGroups) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	dateStr := date.Format("2006-01-02")
+	if _, err := tx.Exec(`DELETE FROM stats WHERE date = ?`, dateStr); err != nil {
+		return fmt.Errorf("failed to clear existing rows for %s: %w", dateStr, err)
+	}
+
+	stmt, err := tx.Prepare(`INSERT INTO stats (date, tag, word, status) VALUES (?, ?, ?, ?)`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare insert: %w", err)
+	}
+	defer stmt.Close()
+
+	for tag, cards := range groups {
+		for word, status := range cards {
+			if _, err := stmt.Exec(dateStr, tag, word, string(status)); err != nil {
+				return fmt.Errorf("failed to insert row for %q: %w", word, err)
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+func (s *sqliteSink) Close() error {
+	return s.db.Close()
+}
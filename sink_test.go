@@ -0,0 +1,86 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTomlSinkWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stats.toml")
+	sink := &tomlSink{path: path}
+
+	day1 := time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC)
+	if err := sink.Write(day1, CardGroups{"": {"hola": StatusNew}}); err != nil {
+		t.Fatalf("Write() day1: %v", err)
+	}
+
+	day2 := time.Date(2025, 1, 16, 0, 0, 0, 0, time.UTC)
+	if err := sink.Write(day2, CardGroups{"es": {"adios": StatusReview}}); err != nil {
+		t.Fatalf("Write() day2: %v", err)
+	}
+
+	byDate, err := parseStatsTOML(path)
+	if err != nil {
+		t.Fatalf("parseStatsTOML: %v", err)
+	}
+
+	if got := string(byDate["2025-01-15"][""]["hola"]); got != string(StatusNew) {
+		t.Errorf("day1 hola status = %q, want %q", got, StatusNew)
+	}
+	if got := string(byDate["2025-01-16"]["es"]["adios"]); got != string(StatusReview) {
+		t.Errorf("day2 adios status = %q, want %q", got, StatusReview)
+	}
+
+	// Re-writing day1 must replace its section in place, not duplicate it or
+	// disturb day2's section.
+	if err := sink.Write(day1, CardGroups{"": {"hola": StatusReview}}); err != nil {
+		t.Fatalf("Write() day1 overwrite: %v", err)
+	}
+
+	byDate, err = parseStatsTOML(path)
+	if err != nil {
+		t.Fatalf("parseStatsTOML after overwrite: %v", err)
+	}
+	if got := string(byDate["2025-01-15"][""]["hola"]); got != string(StatusReview) {
+		t.Errorf("day1 hola status after overwrite = %q, want %q", got, StatusReview)
+	}
+	if got := string(byDate["2025-01-16"]["es"]["adios"]); got != string(StatusReview) {
+		t.Errorf("day2 adios status after day1 overwrite = %q, want %q (should be untouched)", got, StatusReview)
+	}
+}
+
+func TestTomlSinkWriteEscapesQuotes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stats.toml")
+	sink := &tomlSink{path: path}
+
+	day := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	word := `say "hi"`
+	if err := sink.Write(day, CardGroups{"": {word: StatusNew}}); err != nil {
+		t.Fatalf("Write(): %v", err)
+	}
+
+	byDate, err := parseStatsTOML(path)
+	if err != nil {
+		t.Fatalf("parseStatsTOML: %v", err)
+	}
+	if _, ok := byDate["2025-02-01"][""][word]; !ok {
+		t.Errorf("round-tripped word with embedded quotes not found, got groups: %+v", byDate["2025-02-01"])
+	}
+}
+
+func TestTomlSinkWriteMissingFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "nested", "stats.toml")
+	sink := &tomlSink{path: path}
+
+	day := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	if err := sink.Write(day, CardGroups{"": {"word": StatusNew}}); err != nil {
+		t.Fatalf("Write() on fresh path: %v", err)
+	}
+
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("expected stats file to be created: %v", err)
+	}
+}